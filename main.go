@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/hackerfool/SocksProxy/socks5"
+)
+
+func main() {
+	var (
+		showVersion bool
+		listenHost  string
+		listenPort  int
+		userFile    string
+		rulesFile   string
+		metricsAddr string
+		idleTimeout time.Duration
+		rateLimit   int
+	)
+
+	flag.BoolVar(&showVersion, "version", false, "print version")
+	flag.StringVar(&listenHost, "h", "", "listen host")
+	flag.IntVar(&listenPort, "p", 1080, "server port")
+	flag.StringVar(&userFile, "users", "", "path to a username:bcryptHash user list, enables RFC 1929 auth")
+	flag.StringVar(&rulesFile, "rules", "", "path to a YAML/JSON rules config, reloaded on SIGHUP")
+	flag.StringVar(&metricsAddr, "metrics-addr", "", "address to serve Prometheus /metrics on, disabled if empty")
+	flag.DurationVar(&idleTimeout, "idle-timeout", 5*time.Minute, "close a relayed connection after this much inactivity, 0 disables")
+	flag.IntVar(&rateLimit, "rate-limit", 0, "per-connection, per-direction bandwidth cap in bytes/sec, 0 disables")
+	flag.Parse()
+
+	if showVersion {
+		fmt.Println(socks5.Version)
+		return
+	}
+
+	s := socks5.New()
+	s.IdleTimeout = idleTimeout
+	s.RateLimit = rateLimit
+
+	if userFile != "" {
+		userAuth, err := socks5.NewUserPassAuthenticator(userFile)
+		if err != nil {
+			panic(err)
+		}
+		s.AuthMethods = []socks5.Authenticator{userAuth}
+	}
+
+	if rulesFile != "" {
+		re, err := socks5.NewRuleEngine(rulesFile)
+		if err != nil {
+			panic(err)
+		}
+		s.Rules = re
+		socks5.WatchSIGHUP(rulesFile, re)
+	}
+
+	if metricsAddr != "" {
+		socks5.ServeMetrics(metricsAddr)
+	}
+
+	listenAddr := fmt.Sprintf("%s:%d", listenHost, listenPort)
+	s.Addr = listenAddr
+
+	ln, err := net.Listen("tcp", listenAddr)
+	if err != nil {
+		panic(err)
+	}
+
+	log.Println("socks5 proxy run, on addr:", listenAddr)
+	log.Fatal(s.Serve(ln))
+}
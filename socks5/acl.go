@@ -0,0 +1,51 @@
+package socks5
+
+import (
+	"net"
+	"path"
+	"strings"
+)
+
+// Rule is one ACL entry: if User and Dest both match an incoming request,
+// Action decides whether the connection is allowed.
+type Rule struct {
+	User   string // username, or "*" for any authenticated user
+	Dest   string // CIDR ("10.0.0.0/8") or a domain glob ("*.example.com")
+	Action string // "allow" or "deny"
+}
+
+// RuleSet is an ordered list of Rules. The first Rule whose User and Dest
+// both match wins; if none match, the destination is allowed by default.
+type RuleSet []Rule
+
+// Allowed reports whether user is permitted to reach host, which may be an
+// IP literal or a domain name.
+func (rs RuleSet) Allowed(user *User, host string) bool {
+	for _, r := range rs {
+		if r.matchesUser(user) && r.matchesDest(host) {
+			return r.Action == "allow"
+		}
+	}
+	return true
+}
+
+func (r Rule) matchesUser(user *User) bool {
+	if r.User == "" || r.User == "*" {
+		return true
+	}
+	return user != nil && user.Name == r.User
+}
+
+func (r Rule) matchesDest(host string) bool {
+	if strings.Contains(r.Dest, "/") {
+		_, cidr, err := net.ParseCIDR(r.Dest)
+		if err != nil {
+			return false
+		}
+		ip := net.ParseIP(host)
+		return ip != nil && cidr.Contains(ip)
+	}
+
+	ok, err := path.Match(r.Dest, host)
+	return err == nil && ok
+}
@@ -0,0 +1,186 @@
+package socks5
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	methodNoAuth       = 0x00
+	methodGSSAPI       = 0x01
+	methodUserPass     = 0x02
+	methodNoAcceptable = 0xFF
+)
+
+var (
+	errNoAcceptableMethod = errors.New("socks: no acceptable auth method")
+	errAuthFailed         = errors.New("socks: authentication failed")
+	errAuthVer            = errors.New("socks: unsupported auth sub-negotiation version")
+)
+
+// User identifies the principal that completed authentication.
+type User struct {
+	Name string
+}
+
+// Authenticator implements one SOCKS5 authentication method.
+type Authenticator interface {
+	// Method returns the method byte this Authenticator advertises (RFC 1928 section 3).
+	Method() byte
+	// Authenticate runs the method's sub-negotiation on conn and returns the
+	// authenticated User, or an error if the client failed to authenticate.
+	Authenticate(conn net.Conn) (*User, error)
+}
+
+// NoAuthAuthenticator implements the "no authentication required" method.
+type NoAuthAuthenticator struct{}
+
+func (a NoAuthAuthenticator) Method() byte { return methodNoAuth }
+
+func (a NoAuthAuthenticator) Authenticate(conn net.Conn) (*User, error) {
+	return &User{Name: "anonymous"}, nil
+}
+
+// UserPassAuthenticator implements RFC 1929 username/password authentication.
+// Passwords are stored as bcrypt hashes and never held in memory as plaintext.
+type UserPassAuthenticator struct {
+	users map[string][]byte // username -> bcrypt hash
+}
+
+// NewUserPassAuthenticator loads a user list from path. Each line has the form
+// "username:bcryptHash"; blank lines and lines starting with '#' are ignored.
+func NewUserPassAuthenticator(path string) (*UserPassAuthenticator, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	users := make(map[string][]byte)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		users[parts[0]] = []byte(parts[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return &UserPassAuthenticator{users: users}, nil
+}
+
+func (a *UserPassAuthenticator) Method() byte { return methodUserPass }
+
+func (a *UserPassAuthenticator) Authenticate(conn net.Conn) (*User, error) {
+	// 	+----+------+----------+------+----------+
+	// |VER | ULEN |  UNAME   | PLEN |  PASSWD  |
+	// +----+------+----------+------+----------+
+	// | 1  |  1   | 1~255    |  1   | 1~255    |
+	// +----+------+----------+------+----------+
+	buf := make([]byte, 513)
+	readN, err := io.ReadAtLeast(conn, buf, 2)
+	if err != nil {
+		return nil, err
+	}
+	if buf[0] != 0x01 {
+		return nil, errAuthVer
+	}
+
+	ulen := int(buf[1])
+	uEnd := 2 + ulen + 1 // through PLEN, the byte after UNAME
+	if uEnd > readN {
+		if _, err := io.ReadFull(conn, buf[readN:uEnd]); err != nil {
+			return nil, err
+		}
+		readN = uEnd
+	}
+	uname := string(buf[2 : 2+ulen])
+
+	plen := int(buf[2+ulen])
+	pEnd := 2 + ulen + 1 + plen
+	if pEnd > readN {
+		if _, err := io.ReadFull(conn, buf[readN:pEnd]); err != nil {
+			return nil, err
+		}
+	}
+	passwd := buf[2+ulen+1 : pEnd]
+
+	hash, ok := a.users[uname]
+	if !ok || bcrypt.CompareHashAndPassword(hash, passwd) != nil {
+		conn.Write([]byte{0x01, 0x01})
+		return nil, errAuthFailed
+	}
+
+	if _, err := conn.Write([]byte{0x01, 0x00}); err != nil {
+		return nil, err
+	}
+	return &User{Name: uname}, nil
+}
+
+// negotiateAuth performs RFC 1928 method negotiation: it reads the client's
+// advertised methods, picks the strongest Authenticator present in both the
+// client's list and methods, and runs its sub-negotiation.
+func negotiateAuth(conn net.Conn, methods []Authenticator) (*User, error) {
+	// 	+----+----------+----------+
+	// |VER | NMETHODS | METHODS  |
+	// +----+----------+----------+
+	// | 1  |    1     |  1~255   |
+	// +----+----------+----------+
+	buf := make([]byte, 257)
+	readN, err := io.ReadAtLeast(conn, buf, 2)
+	if err != nil {
+		return nil, err
+	}
+	if buf[0] != socksVer5 {
+		return nil, errVer
+	}
+
+	nmethods := int(buf[1])
+	msgLen := 2 + nmethods
+	if msgLen > readN {
+		if _, err := io.ReadFull(conn, buf[readN:msgLen]); err != nil {
+			return nil, err
+		}
+	}
+	offered := buf[2:msgLen]
+
+	chosen := pickAuthenticator(offered, methods)
+	if chosen == nil {
+		conn.Write([]byte{socksVer5, methodNoAcceptable})
+		return nil, errNoAcceptableMethod
+	}
+
+	if _, err := conn.Write([]byte{socksVer5, chosen.Method()}); err != nil {
+		return nil, err
+	}
+
+	return chosen.Authenticate(conn)
+}
+
+// pickAuthenticator returns the first configured Authenticator the client
+// also offered. methods is given in preference order (strongest first), so
+// operators that register UserPass before NoAuth effectively require
+// authentication whenever the client supports it.
+func pickAuthenticator(offered []byte, methods []Authenticator) Authenticator {
+	for _, m := range methods {
+		for _, o := range offered {
+			if o == m.Method() {
+				return m
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package socks5
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUserPassAuthenticateSingleSegment(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("pw"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authr := &UserPassAuthenticator{users: map[string][]byte{"bob": hash}}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	// A single write delivering the whole RFC 1929 message at once is the
+	// common case for real clients (curl, browsers): VER ULEN "bob" PLEN "pw".
+	go client.Write([]byte{0x01, 3, 'b', 'o', 'b', 2, 'p', 'w'})
+	go client.Read(make([]byte, 2)) // drain the status reply so Authenticate's Write doesn't block
+
+	done := make(chan struct{})
+	var user *User
+	go func() {
+		user, err = authr.Authenticate(server)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Authenticate hung reading a single-segment request")
+	}
+
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if user == nil || user.Name != "bob" {
+		t.Fatalf("Authenticate: got user %+v, want bob", user)
+	}
+}
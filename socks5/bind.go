@@ -0,0 +1,40 @@
+package socks5
+
+import (
+	"net"
+	"time"
+)
+
+// handleBind implements the SOCKS5 BIND command (RFC 1928 section 4). It
+// opens a listener on the server, tells the client where to connect a peer
+// back to, waits for that peer, tells the client it arrived, then splices
+// the two connections together.
+func (s *Server) handleBind(conn net.Conn, host string) {
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		sendReply(conn, repForError(err), nil)
+		return
+	}
+	defer ln.Close()
+
+	if err := sendReply(conn, repSucceeded, boundAddr(conn, ln.Addr())); err != nil {
+		return
+	}
+
+	peer, err := ln.Accept()
+	if err != nil {
+		sendReply(conn, repForError(err), nil)
+		return
+	}
+	defer peer.Close()
+
+	s.Logger.DialResult(nil)
+
+	if err := sendReply(conn, repSucceeded, peer.RemoteAddr()); err != nil {
+		return
+	}
+
+	start := time.Now()
+	bytesOut, bytesIn := s.splice(conn, peer)
+	s.Logger.Closed(ClosedEvent{BytesIn: bytesIn, BytesOut: bytesOut, Duration: time.Since(start)})
+}
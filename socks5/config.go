@@ -0,0 +1,148 @@
+package socks5
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"gopkg.in/yaml.v3"
+)
+
+var errNotAllowed = errors.New("socks: destination not allowed by rules")
+
+// RuleConfig is one ACL entry as loaded from the rules config file.
+type RuleConfig struct {
+	User   string `json:"user" yaml:"user"`
+	Dest   string `json:"dest" yaml:"dest"`
+	Action string `json:"action" yaml:"action"`
+}
+
+// UpstreamConfig describes a proxy that selected destinations should be
+// chained through instead of dialing directly.
+type UpstreamConfig struct {
+	Type     string `json:"type" yaml:"type"` // "socks5" or "http-connect"
+	Addr     string `json:"addr" yaml:"addr"`
+	Username string `json:"username" yaml:"username"`
+	Passwd   string `json:"passwd" yaml:"passwd"`
+	Dest     string `json:"dest" yaml:"dest"` // CIDR or domain glob routed through this upstream
+}
+
+// Config is the top-level rules/routing config file, loadable as YAML or
+// JSON and reloadable on SIGHUP.
+type Config struct {
+	Rules     []RuleConfig     `json:"rules" yaml:"rules"`
+	Upstreams []UpstreamConfig `json:"upstreams" yaml:"upstreams"`
+}
+
+// LoadConfig reads and parses a Config from path. YAML is used for .yaml and
+// .yml extensions; everything else is parsed as JSON.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg Config
+	if strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml") {
+		err = yaml.Unmarshal(data, &cfg)
+	} else {
+		err = json.Unmarshal(data, &cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// RuleEngine is what handleConn consults for every CONNECT destination: it
+// enforces the ACL and, for rule-selected destinations, routes through a
+// chained upstream proxy. It can be reloaded in place on SIGHUP.
+type RuleEngine struct {
+	mu     sync.RWMutex
+	rules  RuleSet
+	dialer Dialer
+}
+
+// NewRuleEngine builds a RuleEngine from the config file at path.
+func NewRuleEngine(path string) (*RuleEngine, error) {
+	re := &RuleEngine{}
+	if err := re.Reload(path); err != nil {
+		return nil, err
+	}
+	return re, nil
+}
+
+// Reload re-reads the config file at path and swaps in the new rules and
+// upstream routing atomically.
+func (re *RuleEngine) Reload(path string) error {
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	rules := make(RuleSet, len(cfg.Rules))
+	for i, rc := range cfg.Rules {
+		rules[i] = Rule{User: rc.User, Dest: rc.Dest, Action: rc.Action}
+	}
+
+	perHost := NewPerHost(DirectDialer{})
+	for _, uc := range cfg.Upstreams {
+		var upstream Dialer
+		switch uc.Type {
+		case "socks5":
+			upstream = &Socks5Dialer{Addr: uc.Addr, Username: uc.Username, Passwd: uc.Passwd}
+		case "http-connect":
+			upstream = &HTTPConnectDialer{Addr: uc.Addr, Username: uc.Username, Passwd: uc.Passwd}
+		default:
+			continue
+		}
+		perHost.AddRule(uc.Dest, upstream)
+	}
+
+	re.mu.Lock()
+	re.rules = rules
+	re.dialer = perHost
+	re.mu.Unlock()
+	return nil
+}
+
+// Dial enforces the ACL for user and addr, then dials through the configured
+// direct/upstream routing.
+func (re *RuleEngine) Dial(user *User, network, addr string) (net.Conn, error) {
+	re.mu.RLock()
+	rules, dialer := re.rules, re.dialer
+	re.mu.RUnlock()
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if !rules.Allowed(user, host) {
+		return nil, errNotAllowed
+	}
+
+	return dialer.Dial(network, addr)
+}
+
+// WatchSIGHUP reloads re from path every time the process receives SIGHUP,
+// logging (but not dying on) reload failures so a bad edit doesn't take the
+// server down.
+func WatchSIGHUP(path string, re *RuleEngine) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		for range ch {
+			if err := re.Reload(path); err != nil {
+				log.Println("[ERROR] reload config:", err)
+				continue
+			}
+			log.Println("reloaded config from", path)
+		}
+	}()
+}
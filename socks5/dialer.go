@@ -0,0 +1,56 @@
+package socks5
+
+import "net"
+
+// Dialer abstracts how handleConn reaches a destination, mirroring the
+// golang.org/x/net/proxy Dialer interface so upstream proxies and direct
+// dials are interchangeable.
+type Dialer interface {
+	Dial(network, addr string) (net.Conn, error)
+}
+
+// DirectDialer dials destinations directly with net.Dial.
+type DirectDialer struct{}
+
+func (DirectDialer) Dial(network, addr string) (net.Conn, error) {
+	return net.Dial(network, addr)
+}
+
+// perHostRule pairs a CIDR/domain-glob pattern (same syntax as Rule.Dest)
+// with the Dialer that should handle matching destinations.
+type perHostRule struct {
+	pattern string
+	dialer  Dialer
+}
+
+// PerHost splits dials between a default Dialer and per-pattern overrides,
+// analogous to golang.org/x/net/proxy's PerHost splitter.
+type PerHost struct {
+	def   Dialer
+	rules []perHostRule
+}
+
+// NewPerHost returns a PerHost that falls back to def when no rule matches.
+func NewPerHost(def Dialer) *PerHost {
+	return &PerHost{def: def}
+}
+
+// AddRule routes destinations matching pattern (a CIDR or a domain glob) to
+// dialer instead of the default.
+func (p *PerHost) AddRule(pattern string, dialer Dialer) {
+	p.rules = append(p.rules, perHostRule{pattern, dialer})
+}
+
+func (p *PerHost) Dial(network, addr string) (net.Conn, error) {
+	host := addr
+	if h, _, err := net.SplitHostPort(addr); err == nil {
+		host = h
+	}
+
+	for _, r := range p.rules {
+		if (Rule{Dest: r.pattern}).matchesDest(host) {
+			return r.dialer.Dial(network, addr)
+		}
+	}
+	return p.def.Dial(network, addr)
+}
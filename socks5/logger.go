@@ -0,0 +1,76 @@
+package socks5
+
+import (
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"time"
+)
+
+// RequestEvent describes a parsed SOCKS5 request.
+type RequestEvent struct {
+	User string
+	Cmd  byte
+	Atyp byte
+	Dst  string
+}
+
+// ClosedEvent describes a finished relay.
+type ClosedEvent struct {
+	BytesIn  int64
+	BytesOut int64
+	Duration time.Duration
+}
+
+// Logger receives structured events for each connection's lifecycle, in
+// order: Accepted, HandshakeDone, Request, DialResult, Closed.
+type Logger interface {
+	Accepted(remote net.Addr)
+	HandshakeDone(user *User)
+	Request(evt RequestEvent)
+	DialResult(err error)
+	Closed(evt ClosedEvent)
+}
+
+// slogLogger is the default Logger, emitting each event as a JSON line.
+type slogLogger struct {
+	log *slog.Logger
+}
+
+// NewSlogLogger returns a Logger that writes structured JSON events to w. A
+// nil w defaults to os.Stderr.
+func NewSlogLogger(w io.Writer) Logger {
+	if w == nil {
+		w = os.Stderr
+	}
+	return &slogLogger{log: slog.New(slog.NewJSONHandler(w, nil))}
+}
+
+func (l *slogLogger) Accepted(remote net.Addr) {
+	l.log.Info("accepted", "remote", remote.String())
+}
+
+func (l *slogLogger) HandshakeDone(user *User) {
+	if user == nil {
+		l.log.Info("handshake_done")
+		return
+	}
+	l.log.Info("handshake_done", "user", user.Name)
+}
+
+func (l *slogLogger) Request(evt RequestEvent) {
+	l.log.Info("request", "user", evt.User, "cmd", evt.Cmd, "atyp", evt.Atyp, "dst", evt.Dst)
+}
+
+func (l *slogLogger) DialResult(err error) {
+	if err != nil {
+		l.log.Warn("dial_result", "error", err.Error())
+		return
+	}
+	l.log.Info("dial_result", "ok", true)
+}
+
+func (l *slogLogger) Closed(evt ClosedEvent) {
+	l.log.Info("closed", "bytes_in", evt.BytesIn, "bytes_out", evt.BytesOut, "duration_ms", evt.Duration.Milliseconds())
+}
@@ -0,0 +1,42 @@
+package socks5
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	metricBytesIn = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "socks5_bytes_in_total",
+		Help: "Total bytes relayed from remote destinations to clients.",
+	})
+	metricBytesOut = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "socks5_bytes_out_total",
+		Help: "Total bytes relayed from clients to remote destinations.",
+	})
+	metricActiveConns = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "socks5_active_connections",
+		Help: "Number of connections currently being relayed.",
+	})
+	metricHandshakeFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "socks5_handshake_failures_total",
+		Help: "Total connections that failed auth negotiation or request parsing.",
+	})
+)
+
+// ServeMetrics exposes the Prometheus registry on addr at /metrics.
+func ServeMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	go func() {
+		log.Println("metrics listening on", addr)
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Println("[ERROR] metrics server:", err)
+		}
+	}()
+}
@@ -0,0 +1,129 @@
+package socks5
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"syscall"
+)
+
+// SOCKS5 REP codes (RFC 1928 section 6).
+const (
+	repSucceeded          = 0x00
+	repGeneralFailure     = 0x01
+	repNotAllowed         = 0x02
+	repNetworkUnreachable = 0x03
+	repHostUnreachable    = 0x04
+	repConnRefused        = 0x05
+	repTTLExpired         = 0x06
+	repCmdNotSupported    = 0x07
+	repAddrNotSupported   = 0x08
+)
+
+// sendReply writes a SOCKS5 reply (VER REP RSV ATYP BND.ADDR BND.PORT) to
+// conn. bindAddr populates BND.ADDR/BND.PORT and may be nil, in which case a
+// zeroed IPv4 address is sent, as RFC 1928 permits on failure.
+func sendReply(conn net.Conn, rep byte, bindAddr net.Addr) error {
+	_, err := conn.Write(replyFrame(rep, bindAddr))
+	return err
+}
+
+// replyFrame builds the reply byte frame for rep and bindAddr, which must be
+// a *net.TCPAddr or *net.UDPAddr if non-nil.
+func replyFrame(rep byte, bindAddr net.Addr) []byte {
+	var ip net.IP
+	var port int
+
+	switch a := bindAddr.(type) {
+	case *net.TCPAddr:
+		ip, port = a.IP, a.Port
+	case *net.UDPAddr:
+		ip, port = a.IP, a.Port
+	}
+
+	atyp := byte(typeIPv4)
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	} else if ip != nil {
+		atyp = typeIPv6
+	} else {
+		ip = net.IPv4zero.To4()
+	}
+
+	frame := []byte{socksVer5, rep, 0x00, atyp}
+	frame = append(frame, ip...)
+	portBytes := make([]byte, 2)
+	binary.BigEndian.PutUint16(portBytes, uint16(port))
+	return append(frame, portBytes...)
+}
+
+// boundAddr replaces an unspecified wildcard IP in addr (as produced by
+// net.Listen(":0") or net.ListenPacket(":0")) with the local IP of conn,
+// the connection the client used to reach this server. Clients can't
+// connect a BIND peer to, or aim UDP datagrams at, 0.0.0.0/::, so the
+// reply must name a reachable address. addr must be a *net.TCPAddr or
+// *net.UDPAddr; it's returned unchanged if its IP is already specified or
+// conn's local address isn't a *net.TCPAddr.
+func boundAddr(conn net.Conn, addr net.Addr) net.Addr {
+	var ip net.IP
+	var port int
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		ip, port = a.IP, a.Port
+	case *net.UDPAddr:
+		ip, port = a.IP, a.Port
+	default:
+		return addr
+	}
+	if !ip.IsUnspecified() {
+		return addr
+	}
+
+	local, ok := conn.LocalAddr().(*net.TCPAddr)
+	if !ok {
+		return addr
+	}
+
+	switch addr.(type) {
+	case *net.TCPAddr:
+		return &net.TCPAddr{IP: local.IP, Port: port}
+	case *net.UDPAddr:
+		return &net.UDPAddr{IP: local.IP, Port: port}
+	}
+	return addr
+}
+
+// repForError maps a dial/relay error to the closest matching SOCKS5 REP
+// code, falling back to repGeneralFailure for anything it doesn't recognize.
+func repForError(err error) byte {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		err = opErr.Err
+	}
+
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		switch errno {
+		case syscall.ENETUNREACH:
+			return repNetworkUnreachable
+		case syscall.EHOSTUNREACH, syscall.EHOSTDOWN:
+			return repHostUnreachable
+		case syscall.ECONNREFUSED:
+			return repConnRefused
+		case syscall.ETIMEDOUT:
+			return repTTLExpired
+		}
+	}
+
+	if errors.Is(err, errCmd) {
+		return repCmdNotSupported
+	}
+	if errors.Is(err, errAddrType) {
+		return repAddrNotSupported
+	}
+	if errors.Is(err, errNotAllowed) {
+		return repNotAllowed
+	}
+
+	return repGeneralFailure
+}
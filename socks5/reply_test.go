@@ -0,0 +1,115 @@
+package socks5
+
+import (
+	"bytes"
+	"net"
+	"syscall"
+	"testing"
+)
+
+func TestReplyFrame(t *testing.T) {
+	cases := []struct {
+		name     string
+		rep      byte
+		bindAddr net.Addr
+		want     []byte
+	}{
+		{
+			name:     "nil addr",
+			rep:      repGeneralFailure,
+			bindAddr: nil,
+			want:     []byte{socksVer5, repGeneralFailure, 0x00, typeIPv4, 0, 0, 0, 0, 0, 0},
+		},
+		{
+			name:     "ipv4 tcp addr",
+			rep:      repSucceeded,
+			bindAddr: &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1080},
+			want:     []byte{socksVer5, repSucceeded, 0x00, typeIPv4, 127, 0, 0, 1, 0x04, 0x38},
+		},
+		{
+			name:     "ipv6 udp addr",
+			rep:      repSucceeded,
+			bindAddr: &net.UDPAddr{IP: net.ParseIP("::1"), Port: 53},
+			want: append([]byte{socksVer5, repSucceeded, 0x00, typeIPv6},
+				append(net.ParseIP("::1").To16(), 0x00, 0x35)...),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := replyFrame(c.rep, c.bindAddr)
+			if !bytes.Equal(got, c.want) {
+				t.Errorf("replyFrame(%#x, %v) = %v, want %v", c.rep, c.bindAddr, got, c.want)
+			}
+		})
+	}
+}
+
+func TestBoundAddr(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	localIP := conn.LocalAddr().(*net.TCPAddr).IP
+
+	cases := []struct {
+		name string
+		addr net.Addr
+		want net.Addr
+	}{
+		{
+			name: "wildcard tcp addr gets conn's local IP",
+			addr: &net.TCPAddr{IP: net.IPv4zero, Port: 1080},
+			want: &net.TCPAddr{IP: localIP, Port: 1080},
+		},
+		{
+			name: "wildcard udp addr gets conn's local IP",
+			addr: &net.UDPAddr{IP: net.IPv6zero, Port: 53},
+			want: &net.UDPAddr{IP: localIP, Port: 53},
+		},
+		{
+			name: "already-specified addr is untouched",
+			addr: &net.TCPAddr{IP: net.IPv4(203, 0, 113, 1), Port: 1080},
+			want: &net.TCPAddr{IP: net.IPv4(203, 0, 113, 1), Port: 1080},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := boundAddr(conn, c.addr)
+			if got.String() != c.want.String() {
+				t.Errorf("boundAddr(conn, %v) = %v, want %v", c.addr, got, c.want)
+			}
+		})
+	}
+}
+
+func TestRepForError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want byte
+	}{
+		{"cmd not supported", errCmd, repCmdNotSupported},
+		{"addr not supported", errAddrType, repAddrNotSupported},
+		{"not allowed", errNotAllowed, repNotAllowed},
+		{"unrecognized", errReqExtraData, repGeneralFailure},
+		{"conn refused", &net.OpError{Err: syscall.ECONNREFUSED}, repConnRefused},
+		{"host unreachable", &net.OpError{Err: syscall.EHOSTUNREACH}, repHostUnreachable},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := repForError(c.err); got != c.want {
+				t.Errorf("repForError(%v) = %#x, want %#x", c.err, got, c.want)
+			}
+		})
+	}
+}
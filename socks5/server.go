@@ -0,0 +1,257 @@
+// Package socks5 implements a SOCKS5 proxy server (RFC 1928) that can also
+// be embedded as a net.Dialer/proxy.ContextDialer for Go programs.
+package socks5
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"runtime/debug"
+	"strconv"
+	"time"
+)
+
+const Version = "0.1.0"
+
+var (
+	errAddrType      = errors.New("socks addr type not supported")
+	errVer           = errors.New("socks version not supported")
+	errMethod        = errors.New("socks only support 1 method now")
+	errAuthExtraData = errors.New("socks authentication get extra data")
+	errReqExtraData  = errors.New("socks request get extra data")
+	errCmd           = errors.New("socks command not supported")
+)
+
+const (
+	socksVer5       = 5
+	socksCmdConnect = 1
+
+	proxyConnect = 1
+	proxyBind    = 2
+	proxyUDP     = 3
+
+	typeIPv4 = 1 // type is ipv4 address
+	typeDm   = 3 // type is domain address
+	typeIPv6 = 4 // type is ipv6 address
+
+	ipv4PacketLen = 4 + net.IPv4len + 2
+	ipv6PacketLen = 4 + net.IPv6len + 2
+
+	dmaddrStartPos = 5
+)
+
+// Server is an embeddable SOCKS5 proxy. The zero value is not ready to use;
+// construct one with New.
+type Server struct {
+	// Addr is the address clients use to reach this server. It's only
+	// needed to use the Server itself as a Dial/DialContext client.
+	Addr string
+
+	// AuthMethods are the Authenticators this server accepts, most
+	// preferred first.
+	AuthMethods []Authenticator
+
+	// Rules is consulted for every CONNECT destination.
+	Rules *RuleEngine
+
+	// Logger receives structured events for each connection.
+	Logger Logger
+
+	// IdleTimeout closes a relayed connection after this much inactivity
+	// in either direction. Zero disables the timeout.
+	IdleTimeout time.Duration
+
+	// RateLimit caps each relayed connection, per direction, to this many
+	// bytes/sec. Zero disables the cap.
+	RateLimit int
+}
+
+// New returns a Server with sane defaults: no authentication required,
+// direct dialing with no ACL, a 5 minute idle timeout, and a JSON Logger on
+// stderr.
+func New() *Server {
+	return &Server{
+		AuthMethods: []Authenticator{NoAuthAuthenticator{}},
+		Rules:       &RuleEngine{dialer: DirectDialer{}},
+		Logger:      NewSlogLogger(nil),
+		IdleTimeout: 5 * time.Minute,
+	}
+}
+
+// Serve accepts connections on ln until it returns an error.
+func (s *Server) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// Dial connects to addr through this Server's Addr, acting as a SOCKS5
+// client. It satisfies golang.org/x/net/proxy.Dialer.
+func (s *Server) Dial(network, addr string) (net.Conn, error) {
+	return s.DialContext(context.Background(), network, addr)
+}
+
+// DialContext is Dial with context support, satisfying
+// golang.org/x/net/proxy.ContextDialer.
+func (s *Server) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := &Socks5Dialer{Addr: s.Addr}
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		conn, err := d.Dial(network, addr)
+		ch <- result{conn, err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-ch:
+		return r.conn, r.err
+	}
+}
+
+func (s *Server) handleConn(conn net.Conn) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			log.Println("[ERROR]:", r, "\n", string(debug.Stack()))
+		}
+	}()
+	defer conn.Close()
+
+	s.Logger.Accepted(conn.RemoteAddr())
+
+	user, err := negotiateAuth(conn, s.AuthMethods)
+	if err != nil {
+		metricHandshakeFailures.Inc()
+		return
+	}
+	s.Logger.HandshakeDone(user)
+
+	cmd, atyp, _, host, err := getRequest(conn)
+	if err != nil {
+		metricHandshakeFailures.Inc()
+		sendReply(conn, repForError(err), nil)
+		return
+	}
+	s.Logger.Request(RequestEvent{User: user.Name, Cmd: cmd, Atyp: atyp, Dst: host})
+
+	switch cmd {
+	case proxyBind:
+		s.handleBind(conn, host)
+		return
+	case proxyUDP:
+		s.handleUDPAssociate(conn)
+		return
+	}
+
+	remote, err := s.Rules.Dial(user, "tcp", host)
+	s.Logger.DialResult(err)
+	if err != nil {
+		sendReply(conn, repForError(err), nil)
+		return
+	}
+
+	if err := sendReply(conn, repSucceeded, remote.LocalAddr()); err != nil {
+		return
+	}
+
+	bytesOut, bytesIn := s.splice(conn, remote)
+	s.Logger.Closed(ClosedEvent{BytesIn: bytesIn, BytesOut: bytesOut, Duration: time.Since(start)})
+}
+
+func getRequest(conn net.Conn) (cmd byte, atyp byte, rawAddr []byte, host string, err error) {
+	// 	+----+-----+-------+------+----------+----------+
+	// |VER | CMD |  RSV  | ATYP | DST.ADDR | DST.PORT |
+	// +----+-----+-------+------+----------+----------+
+	// | 1  |  1  |   1   |  1   | Variable |    2     |
+	// +----+-----+-------+------+----------+----------+
+	var (
+		buf    = make([]byte, 263)
+		port   = uint16(0)
+		n      = 0
+		reqLen = -1
+	)
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if n, err = io.ReadAtLeast(conn, buf, dmaddrStartPos); err != nil {
+		return
+	}
+
+	//check ver
+	if buf[0] != socksVer5 {
+		err = errVer
+		return
+	}
+
+	//check cmd
+	cmd = buf[1]
+	switch cmd {
+	case proxyConnect, proxyBind, proxyUDP:
+		//supported
+	default:
+		err = errCmd
+	}
+	if err != nil {
+		return
+	}
+
+	dmLen := int(buf[4])
+	atyp = buf[3]
+
+	switch buf[3] {
+	case typeIPv4:
+		reqLen = ipv4PacketLen
+		host = net.IP(buf[4:(ipv4PacketLen - 2)]).String()
+		port = binary.BigEndian.Uint16(buf[(ipv4PacketLen - 2):])
+	case typeDm:
+		reqLen = (4 + 1 + dmLen + 2)
+		host = string(buf[dmaddrStartPos:(dmaddrStartPos + dmLen)])
+		port = binary.BigEndian.Uint16(buf[(dmaddrStartPos + dmLen):])
+	case typeIPv6:
+		reqLen = ipv6PacketLen
+		host = net.IP(buf[4:(ipv6PacketLen - 2)]).String()
+		port = binary.BigEndian.Uint16(buf[(ipv6PacketLen - 2):])
+	default:
+		err = errAddrType
+		return
+	}
+
+	if n >= reqLen {
+		//
+	} else if n < reqLen {
+		if _, err = io.ReadFull(conn, buf[n:reqLen]); err != nil {
+			return
+		}
+	} else {
+		err = errReqExtraData
+		return
+	}
+
+	switch buf[3] {
+	case typeIPv4:
+		host = net.IP(buf[4:(ipv4PacketLen - 2)]).String()
+		port = binary.BigEndian.Uint16(buf[(ipv4PacketLen - 2):])
+	case typeDm:
+		host = string(buf[dmaddrStartPos:(dmaddrStartPos + dmLen)])
+		port = binary.BigEndian.Uint16(buf[(dmaddrStartPos + dmLen):])
+	case typeIPv6:
+		host = net.IP(buf[4:(ipv6PacketLen - 2)]).String()
+		port = binary.BigEndian.Uint16(buf[(ipv6PacketLen - 2):])
+	}
+
+	host = net.JoinHostPort(host, strconv.Itoa(int(port)))
+
+	return
+}
@@ -0,0 +1,80 @@
+package socks5
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+// TestGetRequestFragmented guards against the off-by-slice bug where the
+// short-read path read into buf[n:(reqLen-n)] instead of buf[n:reqLen]: a
+// domain request arriving in two segments must still be parsed correctly.
+func TestGetRequestFragmented(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	domain := "example.com"
+	req := []byte{socksVer5, proxyConnect, 0x00, typeDm, byte(len(domain))}
+	req = append(req, domain...)
+	req = append(req, 0x00, 0x50) // port 80
+
+	go func() {
+		client.Write(req[:5]) // just enough to satisfy ReadAtLeast's minimum
+		client.Write(req[5:]) // the rest, arriving as a second segment
+	}()
+
+	type result struct {
+		cmd, atyp byte
+		host      string
+		err       error
+	}
+	done := make(chan result, 1)
+	go func() {
+		cmd, atyp, _, host, err := getRequest(server)
+		done <- result{cmd, atyp, host, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatalf("getRequest: %v", r.err)
+		}
+		if r.cmd != proxyConnect || r.atyp != typeDm {
+			t.Fatalf("getRequest: cmd=%d atyp=%d, want %d/%d", r.cmd, r.atyp, proxyConnect, typeDm)
+		}
+		if r.host != "example.com:80" {
+			t.Fatalf("getRequest: host=%q, want %q", r.host, "example.com:80")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("getRequest hung reading a fragmented request")
+	}
+}
+
+// TestGetRequestUnsupportedATYP guards against an unrecognized ATYP falling
+// through to a zero-value host and a misleading general-failure reply
+// instead of errAddrType (REP 0x08, address type not supported).
+func TestGetRequestUnsupportedATYP(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	const atypUnsupported = 0x02 // not IPv4 (1), domain (3), or IPv6 (4)
+	req := []byte{socksVer5, proxyConnect, 0x00, atypUnsupported, 0x00}
+	go client.Write(req)
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, _, _, err := getRequest(server)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != errAddrType {
+			t.Fatalf("getRequest: err = %v, want %v", err, errAddrType)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("getRequest hung reading a request with an unsupported ATYP")
+	}
+}
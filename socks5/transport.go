@@ -0,0 +1,125 @@
+package socks5
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
+)
+
+// bufPool holds the 32KB buffers used to splice connections, avoiding a
+// fresh allocation per direction per connection.
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 32*1024)
+		return &b
+	},
+}
+
+// closeWriter is implemented by *net.TCPConn; splice uses it to half-close
+// a direction on EOF instead of fully closing the connection.
+type closeWriter interface {
+	CloseWrite() error
+}
+
+// splice relays data between a and b in both directions until both sides
+// have finished, then closes both, returning the bytes moved a->b and b->a.
+// Each direction honors s.IdleTimeout and, if s.RateLimit is set, a
+// token-bucket cap; a clean EOF on one side CloseWrites the other so
+// half-closed streams (HTTP/2, WebSocket upgrades) survive.
+func (s *Server) splice(a, b net.Conn) (bytesOut, bytesIn int64) {
+	defer a.Close()
+	defer b.Close()
+
+	metricActiveConns.Inc()
+	defer metricActiveConns.Dec()
+
+	// closeBoth unblocks the peer direction's pending Read as soon as one
+	// direction exits on error, instead of leaving it to notice on its own
+	// (which, with no idle timeout, may be never).
+	var closeOnce sync.Once
+	closeBoth := func() {
+		closeOnce.Do(func() {
+			a.Close()
+			b.Close()
+		})
+	}
+
+	var g errgroup.Group
+	g.Go(func() error {
+		n, err := s.copyDirection(b, a, metricBytesOut)
+		atomic.AddInt64(&bytesOut, n)
+		if err != nil {
+			closeBoth()
+		}
+		return err
+	})
+	g.Go(func() error {
+		n, err := s.copyDirection(a, b, metricBytesIn)
+		atomic.AddInt64(&bytesIn, n)
+		if err != nil {
+			closeBoth()
+		}
+		return err
+	})
+	g.Wait()
+	return
+}
+
+func (s *Server) copyDirection(dst, src net.Conn, counter interface{ Add(float64) }) (int64, error) {
+	bufp := bufPool.Get().(*[]byte)
+	defer bufPool.Put(bufp)
+	buf := *bufp
+
+	var limiter *rate.Limiter
+	if s.RateLimit > 0 {
+		// Burst must cover a full read (buf is 32KB): WaitN rejects any
+		// request for more tokens than the burst allows, which would make
+		// the limiter a silent no-op for rate limits below len(buf).
+		burst := s.RateLimit
+		if burst < len(buf) {
+			burst = len(buf)
+		}
+		limiter = rate.NewLimiter(rate.Limit(s.RateLimit), burst)
+	}
+
+	var total int64
+	for {
+		// Always set the deadline, even when IdleTimeout is 0: getRequest
+		// leaves its own 10s read deadline on the client conn, and failing
+		// to overwrite it here would let that stale deadline fire mid-relay.
+		var deadline time.Time
+		if s.IdleTimeout > 0 {
+			deadline = time.Now().Add(s.IdleTimeout)
+		}
+		src.SetReadDeadline(deadline)
+
+		n, err := src.Read(buf)
+		if n > 0 {
+			if limiter != nil {
+				if werr := limiter.WaitN(context.Background(), n); werr != nil {
+					return total, werr
+				}
+			}
+			total += int64(n)
+			counter.Add(float64(n))
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return total, werr
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				if cw, ok := dst.(closeWriter); ok {
+					cw.CloseWrite()
+				}
+				return total, nil
+			}
+			return total, err
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package socks5
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestCopyDirectionRateLimitBurst guards against the limiter's burst being
+// smaller than a single Read: with a low RateLimit, copyDirection must still
+// be able to pass a full 32KB read through (just throttled), not error out
+// on the first WaitN call.
+func TestCopyDirectionRateLimitBurst(t *testing.T) {
+	srcR, srcW := net.Pipe()
+	dstR, dstW := net.Pipe()
+	defer srcR.Close()
+	defer srcW.Close()
+	defer dstR.Close()
+	defer dstW.Close()
+
+	payload := make([]byte, 32*1024)
+	go func() {
+		srcW.Write(payload)
+		srcW.Close()
+	}()
+	go io.Copy(io.Discard, dstR)
+
+	s := &Server{RateLimit: 1024}
+
+	done := make(chan struct{})
+	var n int64
+	var err error
+	go func() {
+		n, err = s.copyDirection(dstW, srcR, noopCounter{})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("copyDirection did not complete")
+	}
+
+	if err != nil {
+		t.Fatalf("copyDirection: %v", err)
+	}
+	if n != int64(len(payload)) {
+		t.Fatalf("copyDirection: copied %d bytes, want %d", n, len(payload))
+	}
+}
+
+type noopCounter struct{}
+
+func (noopCounter) Add(float64) {}
+
+// TestCopyDirectionClearsStaleDeadline guards against a stale read deadline
+// (e.g. the 10s one getRequest sets on the client conn) surviving into the
+// relay when IdleTimeout is 0: copyDirection must clear it, not just skip
+// refreshing it.
+func TestCopyDirectionClearsStaleDeadline(t *testing.T) {
+	srcR, srcW := net.Pipe()
+	dstR, dstW := net.Pipe()
+	defer srcR.Close()
+	defer srcW.Close()
+	defer dstR.Close()
+	defer dstW.Close()
+
+	srcR.SetReadDeadline(time.Now().Add(-time.Hour))
+
+	go func() {
+		srcW.Write([]byte("hello"))
+		srcW.Close()
+	}()
+	go io.Copy(io.Discard, dstR)
+
+	s := &Server{} // IdleTimeout == 0
+
+	done := make(chan struct{})
+	var err error
+	go func() {
+		_, err = s.copyDirection(dstW, srcR, noopCounter{})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("copyDirection did not complete")
+	}
+
+	if err != nil {
+		t.Fatalf("copyDirection: %v, want nil (stale deadline should have been cleared)", err)
+	}
+}
+
+// TestSpliceClosesPeerOnError guards against a half-dead relay: when one
+// direction exits on a non-EOF error, splice must close both conns so the
+// other direction's blocked Read unblocks promptly instead of waiting for
+// the remote side to notice on its own.
+func TestSpliceClosesPeerOnError(t *testing.T) {
+	a1, a2 := net.Pipe()
+	b1, b2 := net.Pipe()
+	defer a2.Close()
+	defer b2.Close()
+
+	done := make(chan struct{})
+	go func() {
+		s := &Server{}
+		s.splice(a1, b1)
+		close(done)
+	}()
+
+	// Force a hard, non-EOF read error on the a-side direction (standing in
+	// for e.g. a connection reset) without ever writing on b2, so b1's Read
+	// would otherwise block forever.
+	time.Sleep(50 * time.Millisecond)
+	a1.Close()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("splice did not tear down the peer direction after an error")
+	}
+}
@@ -0,0 +1,245 @@
+package socks5
+
+import (
+	"encoding/binary"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// udpHeaderLen is the length of the fixed portion of a SOCKS5 UDP request
+// header (RSV RSV FRAG ATYP), before the variable-length address.
+const udpHeaderLen = 4
+
+// udpAssoc relays UDP datagrams for a single UDP ASSOCIATE session. It locks
+// onto the source address of the first datagram it receives as the
+// associated client and drops datagrams from any other source, so a stray
+// or forged sender to the relay port can't ride along on the association.
+// Each client source address gets its own outbound socket, keyed in nat, so
+// replies from arbitrary remote hosts can be routed back to the right
+// client.
+type udpAssoc struct {
+	relay net.PacketConn
+
+	mu     sync.Mutex
+	client net.Addr                  // the client address this association is bound to, set on first use
+	nat    map[string]net.PacketConn // client addr -> outbound socket used on its behalf
+}
+
+// handleUDPAssociate implements the SOCKS5 UDP ASSOCIATE command (RFC 1928
+// section 4, section 7). It allocates a UDP relay socket, reports its
+// address in the reply, and pumps datagrams until the controlling TCP
+// connection closes.
+func (s *Server) handleUDPAssociate(conn net.Conn) {
+	relay, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		sendReply(conn, repForError(err), nil)
+		return
+	}
+	defer relay.Close()
+
+	if err := sendReply(conn, repSucceeded, boundAddr(conn, relay.LocalAddr())); err != nil {
+		return
+	}
+
+	assoc := &udpAssoc{relay: relay, nat: make(map[string]net.PacketConn)}
+	defer assoc.close()
+	go assoc.pumpFromClients()
+
+	// Tie the association's lifetime to the controlling TCP connection: once
+	// it closes (or errors), tear the relay and all outbound sockets down.
+	// Clear getRequest's 10s read deadline first so it doesn't end the
+	// association while the TCP connection is still open.
+	conn.SetReadDeadline(time.Time{})
+	buf := make([]byte, 1)
+	for {
+		if _, err := conn.Read(buf); err != nil {
+			return
+		}
+	}
+}
+
+func (a *udpAssoc) close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, sock := range a.nat {
+		sock.Close()
+	}
+}
+
+// maxUDPOutbound bounds the number of outbound sockets a single association
+// will allocate, so a client (or, pre-validation, a forged sender) can't
+// exhaust file descriptors by riding an ever-growing nat map.
+const maxUDPOutbound = 256
+
+// pumpFromClients reads datagrams sent to the relay socket, drops anything
+// not from the associated client, and hands each accepted datagram off to
+// forward. Handing off rather than parsing inline keeps a slow DNS lookup
+// for a domain-name DST.ADDR (see parseUDPRequest) from stalling delivery
+// of every other datagram on this association.
+func (a *udpAssoc) pumpFromClients() {
+	buf := make([]byte, 65507)
+	for {
+		n, from, err := a.relay.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if !a.acceptFrom(from) {
+			continue // not the client this association is bound to
+		}
+
+		datagram := make([]byte, n)
+		copy(datagram, buf[:n])
+		go a.forward(datagram, from)
+	}
+}
+
+// acceptFrom reports whether from is the client this association is bound
+// to, binding to the first sender seen and rejecting every other source.
+func (a *udpAssoc) acceptFrom(from net.Addr) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.client == nil {
+		a.client = from
+		return true
+	}
+	return a.client.String() == from.String()
+}
+
+// forward parses datagram as a SOCKS5 UDP request and forwards its payload
+// to DST.ADDR:DST.PORT using the outbound socket for client address from.
+func (a *udpAssoc) forward(datagram []byte, from net.Addr) {
+	dstAddr, payload, err := parseUDPRequest(datagram)
+	if err != nil {
+		return // drop malformed or fragmented (FRAG != 0) datagrams
+	}
+
+	sock := a.outboundFor(from)
+	if sock == nil {
+		return
+	}
+	sock.WriteTo(payload, dstAddr)
+}
+
+// outboundFor returns the outbound socket for client address from, creating
+// one and starting its reply pump on first use. It returns nil once
+// maxUDPOutbound sockets are already allocated.
+func (a *udpAssoc) outboundFor(from net.Addr) net.PacketConn {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if sock, ok := a.nat[from.String()]; ok {
+		return sock
+	}
+	if len(a.nat) >= maxUDPOutbound {
+		return nil
+	}
+
+	sock, err := net.ListenPacket("udp", ":0")
+	if err != nil {
+		log.Println("udp associate: allocate outbound socket:", err)
+		return nil
+	}
+	a.nat[from.String()] = sock
+	go a.pumpReplies(sock, from)
+	return sock
+}
+
+// pumpReplies reads datagrams a remote host sends back on sock, wraps them
+// in a SOCKS5 UDP response header, and returns them to the client at
+// clientAddr via the shared relay socket.
+func (a *udpAssoc) pumpReplies(sock net.PacketConn, clientAddr net.Addr) {
+	buf := make([]byte, 65507)
+	for {
+		n, from, err := sock.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		fromUDP, ok := from.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+		header := udpRequestHeader(fromUDP)
+		a.relay.WriteTo(append(header, buf[:n]...), clientAddr)
+	}
+}
+
+// parseUDPRequest parses the SOCKS5 UDP request header:
+//
+//	+----+------+------+----------+----------+----------+
+//	|RSV | FRAG | ATYP | DST.ADDR | DST.PORT |   DATA   |
+//	+----+------+------+----------+----------+----------+
+//	| 2  |  1   |  1   | Variable |    2     | Variable |
+//	+----+------+------+----------+----------+----------+
+//
+// Fragmented datagrams (FRAG != 0) are not supported and are dropped.
+func parseUDPRequest(buf []byte) (dst *net.UDPAddr, payload []byte, err error) {
+	if len(buf) < udpHeaderLen+1 {
+		return nil, nil, errReqExtraData
+	}
+	if buf[2] != 0 {
+		return nil, nil, errCmd
+	}
+
+	atyp := buf[3]
+	var ip net.IP
+	pos := udpHeaderLen
+
+	switch atyp {
+	case typeIPv4:
+		if len(buf) < pos+net.IPv4len {
+			return nil, nil, errReqExtraData
+		}
+		ip = net.IP(buf[pos : pos+net.IPv4len])
+		pos += net.IPv4len
+	case typeIPv6:
+		if len(buf) < pos+net.IPv6len {
+			return nil, nil, errReqExtraData
+		}
+		ip = net.IP(buf[pos : pos+net.IPv6len])
+		pos += net.IPv6len
+	case typeDm:
+		if len(buf) < pos+1 {
+			return nil, nil, errReqExtraData
+		}
+		dmLen := int(buf[pos])
+		pos++
+		if len(buf) < pos+dmLen {
+			return nil, nil, errReqExtraData
+		}
+		addrs, err := net.LookupIP(string(buf[pos : pos+dmLen]))
+		if err != nil || len(addrs) == 0 {
+			return nil, nil, errAddrType
+		}
+		ip = addrs[0]
+		pos += dmLen
+	default:
+		return nil, nil, errAddrType
+	}
+
+	if len(buf) < pos+2 {
+		return nil, nil, errReqExtraData
+	}
+	port := binary.BigEndian.Uint16(buf[pos : pos+2])
+	pos += 2
+
+	return &net.UDPAddr{IP: ip, Port: int(port)}, buf[pos:], nil
+}
+
+// udpRequestHeader builds a SOCKS5 UDP request header pointing at addr, with
+// FRAG set to 0 (no fragmentation).
+func udpRequestHeader(addr *net.UDPAddr) []byte {
+	atyp := byte(typeIPv4)
+	ip := addr.IP.To4()
+	if ip == nil {
+		atyp = typeIPv6
+		ip = addr.IP.To16()
+	}
+
+	header := []byte{0x00, 0x00, 0x00, atyp}
+	header = append(header, ip...)
+	port := make([]byte, 2)
+	binary.BigEndian.PutUint16(port, uint16(addr.Port))
+	return append(header, port...)
+}
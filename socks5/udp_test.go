@@ -0,0 +1,58 @@
+package socks5
+
+import (
+	"net"
+	"testing"
+)
+
+func TestUDPAssocAcceptFrom(t *testing.T) {
+	a := &udpAssoc{nat: make(map[string]net.PacketConn)}
+
+	client := &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 40000}
+	if !a.acceptFrom(client) {
+		t.Fatal("acceptFrom: first sender should be accepted and bind the association")
+	}
+
+	if !a.acceptFrom(client) {
+		t.Fatal("acceptFrom: the bound client should keep being accepted")
+	}
+
+	stranger := &net.UDPAddr{IP: net.ParseIP("10.0.0.2"), Port: 40000}
+	if a.acceptFrom(stranger) {
+		t.Fatal("acceptFrom: a different source address should be rejected")
+	}
+}
+
+func TestUDPAssocOutboundForCapsNAT(t *testing.T) {
+	a := &udpAssoc{nat: make(map[string]net.PacketConn)}
+	for i := 0; i < maxUDPOutbound; i++ {
+		from := &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 40000 + i}
+		if sock := a.outboundFor(from); sock == nil {
+			t.Fatalf("outboundFor: entry %d: expected a socket, got nil", i)
+		}
+	}
+
+	overflow := &net.UDPAddr{IP: net.ParseIP("10.0.0.1"), Port: 40000 + maxUDPOutbound}
+	if sock := a.outboundFor(overflow); sock != nil {
+		sock.Close()
+		t.Fatal("outboundFor: expected nil once maxUDPOutbound sockets are allocated")
+	}
+
+	a.close()
+}
+
+func TestParseUDPRequestTruncated(t *testing.T) {
+	cases := [][]byte{
+		{0, 0, 0, 1, 1, 2},    // IPv4 ATYP but only 2 of 4 address bytes
+		{0, 0, 0, 4, 1},       // IPv6 ATYP but only 1 of 16 address bytes
+		{0, 0, 0, 3, 10, 'a'}, // domain ATYP claims 10 bytes but only 1 given
+		{0, 0, 0, 1},          // no address or port at all
+		{0, 0, 0},             // shorter than the minimum header
+	}
+
+	for _, buf := range cases {
+		if _, _, err := parseUDPRequest(buf); err == nil {
+			t.Errorf("parseUDPRequest(%v): expected error for truncated datagram, got nil", buf)
+		}
+	}
+}
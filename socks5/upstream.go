@@ -0,0 +1,190 @@
+package socks5
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Socks5Dialer forwards connections through an upstream SOCKS5 proxy,
+// optionally authenticating with RFC 1929 username/password.
+type Socks5Dialer struct {
+	Addr             string
+	Username, Passwd string
+}
+
+func (d *Socks5Dialer) Dial(network, addr string) (net.Conn, error) {
+	conn, err := net.Dial("tcp", d.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	methods := []byte{methodNoAuth}
+	if d.Username != "" {
+		methods = []byte{methodUserPass}
+	}
+	if _, err := conn.Write(append([]byte{socksVer5, byte(len(methods))}, methods...)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if reply[0] != socksVer5 || reply[1] != methods[0] {
+		conn.Close()
+		return nil, errNoAcceptableMethod
+	}
+
+	if reply[1] == methodUserPass {
+		if err := d.authenticate(conn); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	if err := d.connect(conn, addr); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func (d *Socks5Dialer) authenticate(conn net.Conn) error {
+	req := []byte{0x01, byte(len(d.Username))}
+	req = append(req, d.Username...)
+	req = append(req, byte(len(d.Passwd)))
+	req = append(req, d.Passwd...)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[1] != 0x00 {
+		return errAuthFailed
+	}
+	return nil
+}
+
+func (d *Socks5Dialer) connect(conn net.Conn, addr string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	rawAddr, err := encodeSocksAddr(host, portStr)
+	if err != nil {
+		return err
+	}
+
+	req := append([]byte{socksVer5, proxyConnect, 0x00}, rawAddr...)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[1] != repSucceeded {
+		return fmt.Errorf("socks5 upstream: connect failed, rep=0x%02x", header[1])
+	}
+
+	return skipBoundAddr(conn, header[3])
+}
+
+// HTTPConnectDialer forwards connections through an upstream HTTP proxy
+// using the CONNECT method, optionally with Proxy-Authorization.
+type HTTPConnectDialer struct {
+	Addr             string
+	Username, Passwd string
+}
+
+func (d *HTTPConnectDialer) Dial(network, addr string) (net.Conn, error) {
+	conn, err := net.Dial("tcp", d.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if d.Username != "" {
+		req.SetBasicAuth(d.Username, d.Passwd)
+	}
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("http connect upstream: %s", resp.Status)
+	}
+
+	return conn, nil
+}
+
+// encodeSocksAddr builds the ATYP+DST.ADDR+DST.PORT portion of a SOCKS5
+// request for host:port, preferring an IPv4/IPv6 literal and falling back to
+// the domain name type.
+func encodeSocksAddr(host, portStr string) ([]byte, error) {
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, err
+	}
+	portBytes := make([]byte, 2)
+	binary.BigEndian.PutUint16(portBytes, uint16(port))
+
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			return append(append([]byte{typeIPv4}, ip4...), portBytes...), nil
+		}
+		return append(append([]byte{typeIPv6}, ip.To16()...), portBytes...), nil
+	}
+
+	buf := append([]byte{typeDm, byte(len(host))}, host...)
+	return append(buf, portBytes...), nil
+}
+
+// skipBoundAddr consumes the BND.ADDR/BND.PORT bytes that follow a SOCKS5
+// reply header so the connection is left positioned at the relayed stream.
+func skipBoundAddr(conn net.Conn, atyp byte) error {
+	var addrLen int
+	switch atyp {
+	case typeIPv4:
+		addrLen = net.IPv4len
+	case typeIPv6:
+		addrLen = net.IPv6len
+	case typeDm:
+		lenByte := make([]byte, 1)
+		if _, err := io.ReadFull(conn, lenByte); err != nil {
+			return err
+		}
+		addrLen = int(lenByte[0])
+	default:
+		return errAddrType
+	}
+
+	_, err := io.CopyN(io.Discard, conn, int64(addrLen+2))
+	return err
+}